@@ -0,0 +1,129 @@
+// Package calendar renders booking results as an iCalendar (.ics) file and
+// optionally syncs it to a CalDAV server so it can be picked up by a
+// calendar client automatically.
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Booking is the subset of booking data needed to render a VEVENT.
+type Booking struct {
+	CodigoVaga string
+	Location   string
+	Date       string // "2006-01-02"
+	Time       string // "15:04" or "15:04:05"
+	URL        string
+}
+
+// Options controls how events are rendered.
+type Options struct {
+	EventDuration time.Duration
+	AlarmLead     time.Duration
+}
+
+// CalDAVConfig holds the credentials and endpoint used to PUT the generated
+// calendar to a CalDAV server.
+type CalDAVConfig struct {
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+const icsTimeLayout = "20060102T150405Z"
+
+// Render builds a single VCALENDAR document containing one VEVENT per
+// booking.
+func Render(bookings []Booking, opts Options) (string, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString("BEGIN:VCALENDAR\r\n")
+	buf.WriteString("VERSION:2.0\r\n")
+	buf.WriteString("PRODID:-//itep-rn-scheduling//calendar//PT-BR\r\n")
+	buf.WriteString("CALSCALE:GREGORIAN\r\n")
+
+	now := time.Now().UTC().Format(icsTimeLayout)
+
+	for _, booking := range bookings {
+		start, err := time.ParseInLocation("2006-01-02 15:04", booking.Date+" "+booking.Time[:5], time.Local)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse start time for booking %s: %w", booking.CodigoVaga, err)
+		}
+		end := start.Add(opts.EventDuration)
+
+		buf.WriteString("BEGIN:VEVENT\r\n")
+		fmt.Fprintf(&buf, "UID:%s@itep-rn-scheduling\r\n", booking.CodigoVaga)
+		fmt.Fprintf(&buf, "DTSTAMP:%s\r\n", now)
+		fmt.Fprintf(&buf, "DTSTART:%s\r\n", start.UTC().Format(icsTimeLayout))
+		fmt.Fprintf(&buf, "DTEND:%s\r\n", end.UTC().Format(icsTimeLayout))
+		fmt.Fprintf(&buf, "SUMMARY:%s\r\n", escapeText(booking.Location))
+		fmt.Fprintf(&buf, "DESCRIPTION:%s\r\n", escapeText(booking.URL))
+
+		if opts.AlarmLead > 0 {
+			buf.WriteString("BEGIN:VALARM\r\n")
+			buf.WriteString("ACTION:DISPLAY\r\n")
+			fmt.Fprintf(&buf, "DESCRIPTION:%s\r\n", escapeText(booking.Location))
+			fmt.Fprintf(&buf, "TRIGGER:-PT%dM\r\n", int(opts.AlarmLead.Minutes()))
+			buf.WriteString("END:VALARM\r\n")
+		}
+
+		buf.WriteString("END:VEVENT\r\n")
+	}
+
+	buf.WriteString("END:VCALENDAR\r\n")
+
+	return buf.String(), nil
+}
+
+// escapeText escapes commas, semicolons and newlines as required by RFC 5545.
+func escapeText(s string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		`,`, `\,`,
+		`;`, `\;`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(s)
+}
+
+// WriteFile writes the rendered calendar to path on disk.
+func WriteFile(path, ics string) error {
+	return os.WriteFile(path, []byte(ics), 0644)
+}
+
+// PutToCalDAV uploads the rendered calendar to a CalDAV collection using PUT
+// with If-None-Match: * so an existing resource at the same URL is never
+// overwritten.
+func PutToCalDAV(ctx context.Context, cfg CalDAVConfig, filename, ics string) error {
+	target := strings.TrimRight(cfg.URL, "/") + "/" + filename
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, strings.NewReader(ics))
+	if err != nil {
+		return fmt.Errorf("failed to build CalDAV request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	req.Header.Set("If-None-Match", "*")
+	if cfg.Username != "" {
+		req.SetBasicAuth(cfg.Username, cfg.Password)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("CalDAV request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("CalDAV server returned HTTP %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+// Package ledger persists every booking the scheduler has ever obtained in
+// a local SQLite database, so that running the scheduler again (for the
+// same order/date/time, possibly from a different process) never tries to
+// re-book a slot it already holds.
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS bookings (
+	order_id    INTEGER NOT NULL,
+	date        TEXT NOT NULL,
+	time        TEXT NOT NULL,
+	location    TEXT NOT NULL,
+	codigo_vaga TEXT NOT NULL,
+	booked_at   TEXT NOT NULL,
+	expired_at  TEXT,
+	PRIMARY KEY (order_id, date, time)
+);
+`
+
+// Ledger is a SQLite-backed record of every booking obtained by the
+// scheduler, keyed by the (order, date, time) slot it holds.
+type Ledger struct {
+	db *sql.DB
+}
+
+// Open opens (creating if necessary) the ledger database at path.
+func Open(path string) (*Ledger, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger database: %w", err)
+	}
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize ledger schema: %w", err)
+	}
+
+	return &Ledger{db: db}, nil
+}
+
+// Close closes the underlying database handle.
+func (l *Ledger) Close() error {
+	return l.db.Close()
+}
+
+// IsHeld reports whether the scheduler already holds a non-expired booking
+// for the given order/date/time.
+func (l *Ledger) IsHeld(ctx context.Context, orderID int, date, timeStr string) (bool, error) {
+	var exists int
+	err := l.db.QueryRowContext(ctx,
+		`SELECT 1 FROM bookings WHERE order_id = ? AND date = ? AND time = ? AND expired_at IS NULL`,
+		orderID, date, timeStr,
+	).Scan(&exists)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to query ledger: %w", err)
+	}
+
+	return true, nil
+}
+
+// Record stores a newly obtained booking, replacing any expired row for the
+// same slot.
+func (l *Ledger) Record(ctx context.Context, orderID int, date, timeStr, location, codigoVaga string) error {
+	_, err := l.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO bookings (order_id, date, time, location, codigo_vaga, booked_at, expired_at)
+		 VALUES (?, ?, ?, ?, ?, ?, NULL)`,
+		orderID, date, timeStr, location, codigoVaga, time.Now().Format(time.RFC3339),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record booking in ledger: %w", err)
+	}
+	return nil
+}
+
+// ReconcileOrder marks every non-expired booking held for orderID/date as
+// expired if its time slot shows up in availableNow, meaning the server no
+// longer considers it held. It returns how many rows were marked expired.
+func (l *Ledger) ReconcileOrder(ctx context.Context, orderID int, date string, availableNow map[string]bool) (int, error) {
+	rows, err := l.db.QueryContext(ctx,
+		`SELECT time FROM bookings WHERE order_id = ? AND date = ? AND expired_at IS NULL`,
+		orderID, date,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query held bookings: %w", err)
+	}
+
+	var heldTimes []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan held booking: %w", err)
+		}
+		heldTimes = append(heldTimes, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read held bookings: %w", err)
+	}
+
+	expiredAt := time.Now().Format(time.RFC3339)
+	expired := 0
+	for _, t := range heldTimes {
+		if !availableNow[t] {
+			continue
+		}
+
+		if _, err := l.db.ExecContext(ctx,
+			`UPDATE bookings SET expired_at = ? WHERE order_id = ? AND date = ? AND time = ? AND expired_at IS NULL`,
+			expiredAt, orderID, date, t,
+		); err != nil {
+			return expired, fmt.Errorf("failed to mark booking expired: %w", err)
+		}
+		expired++
+	}
+
+	return expired, nil
+}
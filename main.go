@@ -1,27 +1,60 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"os/signal"
+	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"os"
 
+	"github.com/ruangustavo/itep-rn-scheduling/calendar"
+	"github.com/ruangustavo/itep-rn-scheduling/ledger"
+	"github.com/ruangustavo/itep-rn-scheduling/metrics"
+	"github.com/ruangustavo/itep-rn-scheduling/notify"
 	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	Location         string `yaml:"location"`
-	AppointmentCount int    `yaml:"appointment_count"`
-	ScheduleTime     string `yaml:"schedule_time"`
-	BaseURL          string `yaml:"base_url"`
+	Location              string                `yaml:"location"`
+	AppointmentCount      int                   `yaml:"appointment_count"`
+	ScheduleTime          string                `yaml:"schedule_time"`
+	BaseURL               string                `yaml:"base_url"`
+	ICSOutputPath         string                `yaml:"ics_output_path"`
+	EventDurationMinutes  int                   `yaml:"event_duration_minutes"`
+	AlarmLeadMinutes      int                   `yaml:"alarm_lead_minutes"`
+	CalDAV                calendar.CalDAVConfig `yaml:"caldav"`
+	Notifications         []notify.Config       `yaml:"notifications"`
+	MaxRetries            int                   `yaml:"max_retries"`
+	MaxConcurrency        int                   `yaml:"max_concurrency"`
+	MaxConcurrencyCap     int                   `yaml:"max_concurrency_cap"`
+	MetricsAddr           string                `yaml:"metrics_addr"`
+	LedgerPath            string                `yaml:"ledger_path"`
+	Targets               []Target              `yaml:"targets"`
+	RequestTimeoutSeconds int                   `yaml:"request_timeout_seconds"`
+	DryRun                bool                  `yaml:"dry_run"`
+}
+
+// Target describes one (location, set of days) combination the scheduler
+// should plan for. When Config.Targets is left empty, loadConfig synthesizes
+// a single Target from the legacy Location/AppointmentCount fields so old
+// config files keep working.
+type Target struct {
+	Location         string   `yaml:"location"`
+	AppointmentCount int      `yaml:"appointment_count"`
+	DaysAhead        []int    `yaml:"days_ahead"`
+	PreferredTimes   []string `yaml:"preferred_times"`
 }
 
 type Order struct {
@@ -57,35 +90,88 @@ type TimeSlot struct {
 type Scheduler struct {
 	config     Config
 	httpClient *http.Client
+	semaphore  *adaptiveSemaphore
+	ledger     *ledger.Ledger
 }
 
 func main() {
+	metricsAddr := flag.String("metrics-addr", "", "address to serve Prometheus metrics on, e.g. :9090 (overrides Config.MetricsAddr)")
+	cancelStale := flag.Bool("cancel-stale", false, "reconcile the ledger against the server, mark stale bookings as expired, then exit")
+	dryRun := flag.Bool("dry-run", false, "simulate bookings without POSTing to the ITEP API (overrides Config.DryRun)")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	scheduler, err := NewScheduler("config.yaml")
 	if err != nil {
 		log.Fatalf("Failed to initialize scheduler: %v", err)
 	}
+	defer scheduler.Close()
+
+	if *metricsAddr != "" {
+		scheduler.config.MetricsAddr = *metricsAddr
+	}
+	metrics.Serve(scheduler.config.MetricsAddr)
+
+	if *dryRun {
+		scheduler.config.DryRun = true
+	}
+	if scheduler.config.DryRun {
+		log.Printf("Dry-run mode enabled: bookings will be simulated, not sent to the ITEP API")
+	}
 
-	scheduler.waitUntilScheduledTime()
+	if *cancelStale {
+		if err := scheduler.reconcileStaleBookings(ctx); err != nil {
+			log.Fatalf("Failed to reconcile ledger: %v", err)
+		}
+		return
+	}
+
+	scheduler.waitUntilScheduledTime(ctx)
+	if ctx.Err() != nil {
+		log.Printf("Shutting down before scheduling started: %v", ctx.Err())
+		return
+	}
 
-	if err := scheduler.runScheduling(); err != nil {
+	if err := scheduler.runScheduling(ctx); err != nil {
 		log.Fatalf("Scheduling failed: %v", err)
 	}
 }
 
-func NewScheduler(configPath string) (*Scheduler, error) {
+// NewScheduler builds a Scheduler from the config file at configPath. An
+// optional http.RoundTripper may be passed to replace the default HTTP
+// transport, e.g. transport.ReplayRoundTripper in tests.
+func NewScheduler(configPath string, roundTripper ...http.RoundTripper) (*Scheduler, error) {
 	config, err := loadConfig(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	bookingLedger, err := ledger.Open(config.LedgerPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open booking ledger: %w", err)
+	}
+
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+	if len(roundTripper) > 0 && roundTripper[0] != nil {
+		httpClient.Transport = roundTripper[0]
+	}
+
 	return &Scheduler{
-		config: config,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		config:     config,
+		httpClient: httpClient,
+		semaphore:  newAdaptiveSemaphore(config.MaxConcurrency, 1, config.MaxConcurrencyCap),
+		ledger:     bookingLedger,
 	}, nil
 }
 
+// Close releases resources held by the scheduler, such as the ledger
+// database handle.
+func (s *Scheduler) Close() error {
+	return s.ledger.Close()
+}
+
 func loadConfig(path string) (Config, error) {
 	var config Config
 
@@ -93,6 +179,14 @@ func loadConfig(path string) (Config, error) {
 	config.AppointmentCount = 5
 	config.ScheduleTime = "08:00"
 	config.BaseURL = "https://agendamento.itep.rn.gov.br/api"
+	config.ICSOutputPath = "bookings.ics"
+	config.EventDurationMinutes = 30
+	config.AlarmLeadMinutes = 15
+	config.MaxRetries = 5
+	config.MaxConcurrency = 5
+	config.MaxConcurrencyCap = 20
+	config.LedgerPath = "bookings.db"
+	config.RequestTimeoutSeconds = 10
 
 	if data, err := os.ReadFile(path); err == nil {
 		if err := yaml.Unmarshal(data, &config); err != nil {
@@ -106,6 +200,14 @@ func loadConfig(path string) (Config, error) {
 		}
 	}
 
+	if len(config.Targets) == 0 {
+		config.Targets = []Target{{
+			Location:         config.Location,
+			AppointmentCount: config.AppointmentCount,
+			DaysAhead:        []int{1},
+		}}
+	}
+
 	return config, nil
 }
 
@@ -117,7 +219,7 @@ func createDefaultConfig(path string, config Config) error {
 	return os.WriteFile(path, data, 0644)
 }
 
-func (s *Scheduler) waitUntilScheduledTime() {
+func (s *Scheduler) waitUntilScheduledTime(ctx context.Context) {
 	now := time.Now()
 	targetTime, err := time.Parse("15:04", s.config.ScheduleTime)
 	if err != nil {
@@ -136,93 +238,275 @@ func (s *Scheduler) waitUntilScheduledTime() {
 	log.Printf("Waiting until %s to start scheduling (in %v)",
 		target.Format("2006-01-02 15:04:05"), duration)
 
-	time.Sleep(duration)
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		log.Printf("Interrupted while waiting to start: %v", ctx.Err())
+	}
 }
 
-func (s *Scheduler) runScheduling() error {
-	log.Printf("Starting scheduling process for location: %s", s.config.Location)
-	log.Printf("Target appointments: %d", s.config.AppointmentCount)
+// runScheduling iterates the cartesian product of configured targets and
+// their days_ahead offsets, running each (target, day) combination
+// independently so a failure in one doesn't prevent the others from being
+// attempted.
+func (s *Scheduler) runScheduling(ctx context.Context) error {
+	var anySuccess bool
+	var lastErr error
+
+	for _, target := range s.config.Targets {
+		for _, daysAhead := range target.DaysAhead {
+			if ctx.Err() != nil {
+				return fmt.Errorf("scheduling canceled: %w", ctx.Err())
+			}
 
-	orders, err := s.getAvailableOrders()
+			if err := s.runTarget(ctx, target, daysAhead); err != nil {
+				log.Printf("ERROR: scheduling failed for location '%s' (+%d days): %v", target.Location, daysAhead, err)
+				lastErr = err
+				continue
+			}
+			anySuccess = true
+		}
+	}
+
+	if !anySuccess {
+		return fmt.Errorf("all targets failed: %w", lastErr)
+	}
+
+	return nil
+}
+
+// runTarget runs the full scheduling flow for a single (location, day)
+// combination: fetch orders, collect slots, pick unique ones not already
+// held in the ledger, book them concurrently, then export/notify.
+func (s *Scheduler) runTarget(ctx context.Context, target Target, daysAhead int) error {
+	targetDate := s.resolveTargetDate(daysAhead)
+	targetDateStr := targetDate.Format("2006-01-02")
+
+	log.Printf("Starting scheduling process for location: %s (%s)", target.Location, targetDateStr)
+	log.Printf("Target appointments: %d", target.AppointmentCount)
+
+	orders, err := s.getAvailableOrders(ctx, targetDate)
 	if err != nil {
 		return fmt.Errorf("failed to get orders: %w", err)
 	}
 
 	var targetOrders []Order
 	for _, order := range orders {
-		if order.Location.Nome == s.config.Location {
+		if order.Location.Nome == target.Location {
 			targetOrders = append(targetOrders, order)
 		}
 	}
 
 	if len(targetOrders) == 0 {
-		log.Printf("ERROR: No orders found for location '%s' on %s", s.config.Location, s.getNextWorkingDay().Format("2006-01-02"))
 		return fmt.Errorf("no orders available for specified location on target date")
 	}
 
-	log.Printf("Found %d orders for location '%s'", len(targetOrders), s.config.Location)
+	log.Printf("Found %d orders for location '%s'", len(targetOrders), target.Location)
 
-	allSlots, err := s.collectAllTimeSlots(targetOrders)
+	allSlots, err := s.collectAllTimeSlots(ctx, targetOrders, targetDateStr)
 	if err != nil {
 		return fmt.Errorf("failed to collect time slots: %w", err)
 	}
 
 	if len(allSlots) == 0 {
-		log.Printf("ERROR: No available time slots found for location '%s' on %s", s.config.Location, s.getNextWorkingDay().Format("2006-01-02"))
 		return fmt.Errorf("no time slots available")
 	}
 
 	log.Printf("Found %d total time slots", len(allSlots))
 
-	slotsToBook := s.selectUniqueSlots(allSlots)
+	slotsToBook := s.selectUniqueSlots(ctx, target, allSlots)
 
 	if len(slotsToBook) == 0 {
-		log.Printf("ERROR: No unique time slots available for booking")
 		return fmt.Errorf("no unique slots available")
 	}
 
 	actualBookings := len(slotsToBook)
-	if actualBookings < s.config.AppointmentCount {
+	if actualBookings < target.AppointmentCount {
 		log.Printf("WARNING: Only %d slots available, booking fewer than requested %d",
-			actualBookings, s.config.AppointmentCount)
+			actualBookings, target.AppointmentCount)
 	}
 
-	bookingResults := s.bookAppointmentsConcurrently(slotsToBook)
+	bookingResults := s.bookAppointmentsConcurrently(ctx, slotsToBook)
 
 	successCount := 0
 	var successfulBookings []BookingResponse
+	var bookedSlots []TimeSlot
+	var bookingErrors []error
 
 	for i, result := range bookingResults {
 		if result.err != nil {
 			log.Printf("Booking %d failed: %v", i+1, result.err)
+			bookingErrors = append(bookingErrors, result.err)
 		} else {
 			successCount++
 			successfulBookings = append(successfulBookings, result.response)
+			bookedSlots = append(bookedSlots, slotsToBook[i])
 			log.Printf("Booking %d successful: Code %s", i+1, result.response.CodigoVaga)
+
+			slot := slotsToBook[i]
+			if s.config.DryRun {
+				continue
+			}
+			if err := s.ledger.Record(ctx, slot.OrderID, slot.Date, slot.Time, target.Location, result.response.CodigoVaga); err != nil {
+				log.Printf("WARNING: failed to record booking in ledger: %v", err)
+			}
 		}
 	}
 
 	if successCount == 0 {
-		log.Printf("ERROR: All booking attempts failed")
 		return fmt.Errorf("all booking attempts failed")
 	}
 
-	log.Printf("\n=== SCHEDULING COMPLETED SUCCESSFULLY ===")
+	log.Printf("\n=== SCHEDULING COMPLETED SUCCESSFULLY for %s (%s) ===", target.Location, targetDateStr)
 	log.Printf("Successfully booked %d out of %d attempted appointments", successCount, len(slotsToBook))
 	log.Printf("\nBooking links:")
 
-	for _, booking := range successfulBookings {
+	var calendarBookings []calendar.Booking
+	var notifyBookings []notify.BookingResponse
+	for i, booking := range successfulBookings {
 		link := fmt.Sprintf("https://agendamento.itep.rn.gov.br/public/agendamento/%s/agendar",
 			booking.CodigoVaga)
 		fmt.Println(link)
+
+		calendarBookings = append(calendarBookings, calendar.Booking{
+			CodigoVaga: booking.CodigoVaga,
+			Location:   target.Location,
+			Date:       bookedSlots[i].Date,
+			Time:       bookedSlots[i].Time,
+			URL:        link,
+		})
+		notifyBookings = append(notifyBookings, notify.BookingResponse{
+			CodigoVaga: booking.CodigoVaga,
+			Link:       link,
+		})
+	}
+
+	if err := s.exportCalendar(ctx, calendarBookings); err != nil {
+		log.Printf("WARNING: failed to export calendar: %v", err)
 	}
 
+	s.notifyResults(notify.Summary{
+		Location:  target.Location,
+		Date:      targetDateStr,
+		Successes: notifyBookings,
+		Failures:  bookingErrors,
+	})
+
 	return nil
 }
 
-func (s *Scheduler) getAvailableOrders() ([]Order, error) {
-	targetDate := s.getNextWorkingDay()
+// reconcileStaleBookings re-fetches available times for every configured
+// target/day and marks ledger rows expired for any slot that shows up as
+// available again. The ITEP API has no endpoint to list existing bookings
+// by codigo_vaga, so this is a best-effort heuristic: a slot we hold
+// wouldn't normally appear as available, so if it does the server must have
+// released it.
+func (s *Scheduler) reconcileStaleBookings(ctx context.Context) error {
+	for _, target := range s.config.Targets {
+		for _, daysAhead := range target.DaysAhead {
+			if ctx.Err() != nil {
+				return fmt.Errorf("reconciliation canceled: %w", ctx.Err())
+			}
+
+			targetDate := s.resolveTargetDate(daysAhead)
+			targetDateStr := targetDate.Format("2006-01-02")
+
+			orders, err := s.getAvailableOrders(ctx, targetDate)
+			if err != nil {
+				log.Printf("Failed to get orders for location '%s' on %s: %v", target.Location, targetDateStr, err)
+				continue
+			}
+
+			for _, order := range orders {
+				if order.Location.Nome != target.Location {
+					continue
+				}
+
+				times, err := s.getAvailableTimes(ctx, order.ID, targetDateStr)
+				if err != nil {
+					log.Printf("Failed to get times for order %d on %s: %v", order.ID, targetDateStr, err)
+					continue
+				}
+
+				availableNow := make(map[string]bool, len(times))
+				for _, t := range times {
+					availableNow[t] = true
+				}
+
+				expired, err := s.ledger.ReconcileOrder(ctx, order.ID, targetDateStr, availableNow)
+				if err != nil {
+					log.Printf("Failed to reconcile order %d on %s: %v", order.ID, targetDateStr, err)
+					continue
+				}
+				if expired > 0 {
+					log.Printf("Marked %d stale booking(s) expired for order %d on %s", expired, order.ID, targetDateStr)
+				}
+			}
+		}
+	}
 
+	return nil
+}
+
+// notifyResults fans the run's outcome out to every configured notification
+// sink, logging (rather than failing the run on) any sink error.
+func (s *Scheduler) notifyResults(summary notify.Summary) {
+	if len(s.config.Notifications) == 0 {
+		return
+	}
+
+	sinks, err := notify.LoadSinks(s.config.Notifications)
+	if err != nil {
+		log.Printf("WARNING: failed to load notification sinks: %v", err)
+		return
+	}
+
+	for _, err := range notify.Dispatch(context.Background(), sinks, summary) {
+		log.Printf("WARNING: notification sink failed: %v", err)
+	}
+}
+
+// exportCalendar renders the successful bookings as an ICS file, writing it
+// to disk and/or syncing it to a CalDAV endpoint depending on what's
+// configured.
+func (s *Scheduler) exportCalendar(ctx context.Context, bookings []calendar.Booking) error {
+	if len(bookings) == 0 {
+		return nil
+	}
+
+	ics, err := calendar.Render(bookings, calendar.Options{
+		EventDuration: time.Duration(s.config.EventDurationMinutes) * time.Minute,
+		AlarmLead:     time.Duration(s.config.AlarmLeadMinutes) * time.Minute,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render calendar: %w", err)
+	}
+
+	if s.config.ICSOutputPath != "" {
+		if err := calendar.WriteFile(s.config.ICSOutputPath, ics); err != nil {
+			return fmt.Errorf("failed to write ICS file: %w", err)
+		}
+		log.Printf("Wrote calendar export to %s", s.config.ICSOutputPath)
+	}
+
+	if s.config.CalDAV.URL != "" {
+		filename := filepath.Base(s.config.ICSOutputPath)
+		if filename == "" || filename == "." {
+			filename = "bookings.ics"
+		}
+		if err := calendar.PutToCalDAV(ctx, s.config.CalDAV, filename, ics); err != nil {
+			return fmt.Errorf("failed to sync calendar to CalDAV: %w", err)
+		}
+		log.Printf("Synced calendar export to CalDAV endpoint")
+	}
+
+	return nil
+}
+
+func (s *Scheduler) getAvailableOrders(ctx context.Context, targetDate time.Time) ([]Order, error) {
 	url := fmt.Sprintf("%s/ordens/public?data_inicial=%s&data_final=%s",
 		s.config.BaseURL,
 		targetDate.Format("2006-01-02"),
@@ -230,7 +514,7 @@ func (s *Scheduler) getAvailableOrders() ([]Order, error) {
 
 	log.Printf("Fetching orders for %s only", targetDate.Format("2006-01-02"))
 
-	resp, err := s.httpClient.Get(url)
+	resp, err := s.doRequest(ctx, http.MethodGet, url, nil, nil)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP request failed: %w", err)
 	}
@@ -251,23 +535,23 @@ func (s *Scheduler) getAvailableOrders() ([]Order, error) {
 	return orders, nil
 }
 
-func (s *Scheduler) getNextWorkingDay() time.Time {
-	tomorrow := time.Now().AddDate(0, 0, 1)
+// resolveTargetDate resolves a days_ahead offset to a calendar date,
+// skipping forward over weekends the same way the scheduler always has.
+func (s *Scheduler) resolveTargetDate(daysAhead int) time.Time {
+	date := time.Now().AddDate(0, 0, daysAhead)
 
-	for tomorrow.Weekday() == time.Saturday || tomorrow.Weekday() == time.Sunday {
-		tomorrow = tomorrow.AddDate(0, 0, 1)
+	for date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		date = date.AddDate(0, 0, 1)
 	}
 
-	return tomorrow
+	return date
 }
 
-func (s *Scheduler) collectAllTimeSlots(orders []Order) ([]TimeSlot, error) {
+func (s *Scheduler) collectAllTimeSlots(ctx context.Context, orders []Order, targetDateStr string) ([]TimeSlot, error) {
 	var allSlots []TimeSlot
-	targetDate := s.getNextWorkingDay()
-	targetDateStr := targetDate.Format("2006-01-02")
 
 	for _, order := range orders {
-		dates, err := s.getAvailableDates(order.ID)
+		dates, err := s.getAvailableDates(ctx, order.ID)
 		if err != nil {
 			log.Printf("Failed to get dates for order %d: %v", order.ID, err)
 			continue
@@ -286,7 +570,7 @@ func (s *Scheduler) collectAllTimeSlots(orders []Order) ([]TimeSlot, error) {
 			continue
 		}
 
-		times, err := s.getAvailableTimes(order.ID, targetDateStr)
+		times, err := s.getAvailableTimes(ctx, order.ID, targetDateStr)
 		if err != nil {
 			log.Printf("Failed to get times for order %d, date %s: %v", order.ID, targetDateStr, err)
 			continue
@@ -308,10 +592,10 @@ func (s *Scheduler) collectAllTimeSlots(orders []Order) ([]TimeSlot, error) {
 	return allSlots, nil
 }
 
-func (s *Scheduler) getAvailableDates(orderID int) ([]string, error) {
+func (s *Scheduler) getAvailableDates(ctx context.Context, orderID int) ([]string, error) {
 	url := fmt.Sprintf("%s/ordens/public/datas?ordem=%d", s.config.BaseURL, orderID)
 
-	resp, err := s.httpClient.Get(url)
+	resp, err := s.doRequest(ctx, http.MethodGet, url, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -332,7 +616,7 @@ func (s *Scheduler) getAvailableDates(orderID int) ([]string, error) {
 	return dates, nil
 }
 
-func (s *Scheduler) getAvailableTimes(orderID int, dateStr string) ([]string, error) {
+func (s *Scheduler) getAvailableTimes(ctx context.Context, orderID int, dateStr string) ([]string, error) {
 	date, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
 		return nil, err
@@ -347,7 +631,7 @@ func (s *Scheduler) getAvailableTimes(orderID int, dateStr string) ([]string, er
 
 	log.Printf("Requesting times from URL: %s", apiURL)
 
-	resp, err := s.httpClient.Get(apiURL)
+	resp, err := s.doRequest(ctx, http.MethodGet, apiURL, nil, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -372,18 +656,53 @@ func (s *Scheduler) getAvailableTimes(orderID int, dateStr string) ([]string, er
 	return times, nil
 }
 
-func (s *Scheduler) selectUniqueSlots(allSlots []TimeSlot) []TimeSlot {
+// selectUniqueSlots picks up to target.AppointmentCount slots with distinct
+// times, skipping any slot the ledger already shows as held so repeated
+// runs never try to re-book the same (order, date, time). When
+// target.PreferredTimes is set, candidates matching it are tried first.
+func (s *Scheduler) selectUniqueSlots(ctx context.Context, target Target, allSlots []TimeSlot) []TimeSlot {
+	var preferred map[string]bool
+	if len(target.PreferredTimes) > 0 {
+		preferred = make(map[string]bool, len(target.PreferredTimes))
+		for _, t := range target.PreferredTimes {
+			preferred[shortTime(t)] = true
+		}
+	}
+
 	usedTimes := make(map[string]bool)
-	var selectedSlots []TimeSlot
+	var candidates []TimeSlot
 
 	for _, slot := range allSlots {
-		if !usedTimes[slot.Time] && len(selectedSlots) < s.config.AppointmentCount {
-			usedTimes[slot.Time] = true
-			selectedSlots = append(selectedSlots, slot)
+		if usedTimes[slot.Time] {
+			continue
 		}
+
+		held, err := s.ledger.IsHeld(ctx, slot.OrderID, slot.Date, slot.Time)
+		if err != nil {
+			log.Printf("WARNING: failed to check ledger for order %d: %v", slot.OrderID, err)
+		} else if held {
+			continue
+		}
+
+		usedTimes[slot.Time] = true
+		candidates = append(candidates, slot)
 	}
 
-	log.Printf("Selected %d unique time slots for booking on %s", len(selectedSlots), s.getNextWorkingDay().Format("2006-01-02"))
+	if preferred != nil {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return preferred[shortTime(candidates[i].Time)] && !preferred[shortTime(candidates[j].Time)]
+		})
+	}
+
+	var selectedSlots []TimeSlot
+	for _, slot := range candidates {
+		if len(selectedSlots) >= target.AppointmentCount {
+			break
+		}
+		selectedSlots = append(selectedSlots, slot)
+	}
+
+	log.Printf("Selected %d unique time slots for booking on %s", len(selectedSlots), target.Location)
 	for i, slot := range selectedSlots {
 		log.Printf("Slot %d: Order %d, Date %s, Time %s",
 			i+1, slot.OrderID, slot.Date, slot.Time)
@@ -392,29 +711,46 @@ func (s *Scheduler) selectUniqueSlots(allSlots []TimeSlot) []TimeSlot {
 	return selectedSlots
 }
 
+// shortTime normalizes a time string to "HH:MM" so times coming from the
+// ITEP API ("15:04:05") can be compared against config-supplied
+// preferred_times ("15:04").
+func shortTime(t string) string {
+	if len(t) >= 5 {
+		return t[:5]
+	}
+	return t
+}
+
 type BookingResult struct {
 	response BookingResponse
 	err      error
 }
 
-func (s *Scheduler) bookAppointmentsConcurrently(slots []TimeSlot) []BookingResult {
+// bookAppointmentsConcurrently books every slot concurrently, bounded by the
+// adaptive semaphore. If ctx is canceled, goroutines that haven't yet
+// acquired a slot abort without booking and bookings already in flight
+// have their HTTP requests canceled; both record ctx's error, while
+// bookings that already completed keep their results, so the caller still
+// gets partial results.
+func (s *Scheduler) bookAppointmentsConcurrently(ctx context.Context, slots []TimeSlot) []BookingResult {
 	results := make([]BookingResult, len(slots))
 	var wg sync.WaitGroup
 
-	semaphore := make(chan struct{}, 5)
-
 	for i, slot := range slots {
 		wg.Add(1)
 		go func(index int, timeSlot TimeSlot) {
 			defer wg.Done()
 
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			if err := s.semaphore.acquire(ctx); err != nil {
+				results[index] = BookingResult{err: err}
+				return
+			}
+			defer s.semaphore.release()
 
 			log.Printf("Attempting booking %d: Order %d, Date %s, Time %s",
 				index+1, timeSlot.OrderID, timeSlot.Date, timeSlot.Time)
 
-			response, err := s.bookAppointment(timeSlot)
+			response, err := s.bookAppointment(ctx, timeSlot)
 			results[index] = BookingResult{
 				response: response,
 				err:      err,
@@ -426,7 +762,16 @@ func (s *Scheduler) bookAppointmentsConcurrently(slots []TimeSlot) []BookingResu
 	return results
 }
 
-func (s *Scheduler) bookAppointment(slot TimeSlot) (BookingResponse, error) {
+func (s *Scheduler) bookAppointment(ctx context.Context, slot TimeSlot) (BookingResponse, error) {
+	if s.config.DryRun {
+		log.Printf("[dry-run] Skipping booking POST for order %d, date %s, time %s",
+			slot.OrderID, slot.Date, slot.Time)
+		return BookingResponse{
+			CodigoVaga: fmt.Sprintf("DRYRUN-%d-%s-%s", slot.OrderID, slot.Date, strings.ReplaceAll(slot.Time, ":", "")),
+			Agendou:    1,
+		}, nil
+	}
+
 	date, err := time.Parse("2006-01-02", slot.Date)
 	if err != nil {
 		return BookingResponse{}, fmt.Errorf("invalid date format: %w", err)
@@ -450,7 +795,9 @@ func (s *Scheduler) bookAppointment(slot TimeSlot) (BookingResponse, error) {
 	log.Printf("Booking request body: %s", string(requestBody))
 
 	url := fmt.Sprintf("%s/vagas", s.config.BaseURL)
-	resp, err := s.httpClient.Post(url, "application/json", bytes.NewBuffer(requestBody))
+	resp, err := s.doRequest(ctx, http.MethodPost, url, requestBody, map[string]string{
+		"Content-Type": "application/json",
+	})
 	if err != nil {
 		return BookingResponse{}, fmt.Errorf("HTTP request failed: %w", err)
 	}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ruangustavo/itep-rn-scheduling/ledger"
+	"github.com/ruangustavo/itep-rn-scheduling/transport"
+)
+
+const fixturesPath = "testdata/fixtures.json"
+
+func newFixtureScheduler(t *testing.T) *Scheduler {
+	t.Helper()
+
+	rt, err := transport.LoadReplayRoundTripper(fixturesPath)
+	if err != nil {
+		t.Fatalf("failed to load fixtures: %v", err)
+	}
+
+	return &Scheduler{
+		config: Config{
+			BaseURL:    "https://itep.example.test/api",
+			MaxRetries: 1,
+		},
+		httpClient: &http.Client{Transport: rt},
+		semaphore:  newAdaptiveSemaphore(1, 1, 1),
+	}
+}
+
+func TestGetAvailableOrders(t *testing.T) {
+	s := newFixtureScheduler(t)
+	targetDate := time.Date(2026, time.August, 3, 0, 0, 0, 0, time.UTC)
+
+	orders, err := s.getAvailableOrders(context.Background(), targetDate)
+	if err != nil {
+		t.Fatalf("getAvailableOrders returned error: %v", err)
+	}
+
+	if len(orders) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(orders))
+	}
+	if orders[0].ID != 101 || orders[0].Location.Nome != "SAO MIGUEL" {
+		t.Errorf("unexpected first order: %+v", orders[0])
+	}
+}
+
+func TestGetAvailableDates(t *testing.T) {
+	s := newFixtureScheduler(t)
+
+	dates, err := s.getAvailableDates(context.Background(), 101)
+	if err != nil {
+		t.Fatalf("getAvailableDates returned error: %v", err)
+	}
+
+	want := []string{"2026-08-03", "2026-08-04"}
+	if len(dates) != len(want) {
+		t.Fatalf("expected %d dates, got %d", len(want), len(dates))
+	}
+	for i, d := range want {
+		if dates[i] != d {
+			t.Errorf("dates[%d] = %q, want %q", i, dates[i], d)
+		}
+	}
+}
+
+func TestGetAvailableTimes(t *testing.T) {
+	s := newFixtureScheduler(t)
+
+	times, err := s.getAvailableTimes(context.Background(), 101, "2026-08-03")
+	if err != nil {
+		t.Fatalf("getAvailableTimes returned error: %v", err)
+	}
+
+	if len(times) != 3 {
+		t.Fatalf("expected 3 times, got %d", len(times))
+	}
+	if times[0] != "09:00:00" {
+		t.Errorf("times[0] = %q, want %q", times[0], "09:00:00")
+	}
+}
+
+func TestSelectUniqueSlots(t *testing.T) {
+	bookingLedger, err := ledger.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open ledger: %v", err)
+	}
+	defer bookingLedger.Close()
+
+	s := &Scheduler{ledger: bookingLedger}
+
+	target := Target{Location: "SAO MIGUEL", AppointmentCount: 2}
+	allSlots := []TimeSlot{
+		{OrderID: 101, Date: "2026-08-03", Time: "09:00:00"},
+		{OrderID: 101, Date: "2026-08-03", Time: "09:00:00"}, // duplicate time, should be skipped
+		{OrderID: 101, Date: "2026-08-03", Time: "10:00:00"},
+		{OrderID: 102, Date: "2026-08-03", Time: "11:00:00"},
+	}
+
+	ctx := context.Background()
+	if err := bookingLedger.Record(ctx, 101, "2026-08-03", "10:00:00", target.Location, "ALREADY-HELD"); err != nil {
+		t.Fatalf("failed to seed ledger: %v", err)
+	}
+
+	selected := s.selectUniqueSlots(ctx, target, allSlots)
+
+	if len(selected) != 2 {
+		t.Fatalf("expected 2 selected slots, got %d", len(selected))
+	}
+	for _, slot := range selected {
+		if slot.Time == "10:00:00" {
+			t.Errorf("slot already held by ledger was selected: %+v", slot)
+		}
+	}
+}
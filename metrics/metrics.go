@@ -0,0 +1,58 @@
+// Package metrics exposes Prometheus counters for the scheduler's HTTP
+// traffic so a container running it headless can alert on failure rates.
+package metrics
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// Attempts counts every HTTP request attempt, including retries.
+	Attempts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "itep_scheduler_requests_total",
+		Help: "Total number of HTTP request attempts made to the ITEP API.",
+	})
+
+	// Retries counts attempts that were retried after a network error,
+	// 429, or 5xx response.
+	Retries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "itep_scheduler_retries_total",
+		Help: "Total number of HTTP requests retried.",
+	})
+
+	// RateLimited counts HTTP 429 responses.
+	RateLimited = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "itep_scheduler_rate_limited_total",
+		Help: "Total number of HTTP 429 responses received.",
+	})
+
+	// Successes counts requests that completed without a network error
+	// and with a status below 400.
+	Successes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "itep_scheduler_requests_success_total",
+		Help: "Total number of HTTP requests that completed successfully.",
+	})
+)
+
+// Serve starts a background HTTP server exposing /metrics at addr. It is a
+// no-op when addr is empty.
+func Serve(addr string) {
+	if addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		log.Printf("Serving Prometheus metrics on %s/metrics", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Metrics server stopped: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// DiscordConfig configures delivery via a Discord incoming webhook.
+type DiscordConfig struct {
+	WebhookURL string `yaml:"webhook_url"`
+}
+
+type discordSink struct {
+	config DiscordConfig
+	client *http.Client
+}
+
+// NewDiscordSink builds a Sink that posts to a Discord webhook.
+func NewDiscordSink(config DiscordConfig) Sink {
+	return &discordSink{config: config, client: &http.Client{}}
+}
+
+func (d *discordSink) Notify(ctx context.Context, summary Summary) error {
+	message, err := renderMessage(summary)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]string{"content": message})
+	if err != nil {
+		return fmt.Errorf("failed to marshal Discord payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.config.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build Discord request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Discord webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("Discord webhook returned HTTP %d", resp.StatusCode)
+	}
+
+	return nil
+}
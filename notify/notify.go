@@ -0,0 +1,110 @@
+// Package notify fans out the result of a scheduling run to a configurable
+// set of sinks (Telegram, Discord, email, generic webhooks, ...) so the
+// scheduler can run headless under cron/systemd and still tell someone what
+// happened.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"text/template"
+)
+
+// BookingResponse is the minimal booking data a sink needs to render a
+// notification.
+type BookingResponse struct {
+	CodigoVaga string
+	Link       string
+}
+
+// Summary describes the outcome of a single scheduling run.
+type Summary struct {
+	Location  string
+	Date      string
+	Successes []BookingResponse
+	Failures  []error
+}
+
+// Sink delivers a Summary to some external destination.
+type Sink interface {
+	Notify(ctx context.Context, summary Summary) error
+}
+
+// Config is the YAML shape of a single entry under Config.Notifications. The
+// Type field selects which nested config is used.
+type Config struct {
+	Type     string          `yaml:"type"`
+	Telegram *TelegramConfig `yaml:"telegram,omitempty"`
+	Discord  *DiscordConfig  `yaml:"discord,omitempty"`
+	SMTP     *SMTPConfig     `yaml:"smtp,omitempty"`
+	Webhook  *WebhookConfig  `yaml:"webhook,omitempty"`
+}
+
+// LoadSinks builds the concrete Sink for each enabled entry in configs.
+func LoadSinks(configs []Config) ([]Sink, error) {
+	var sinks []Sink
+
+	for i, cfg := range configs {
+		switch cfg.Type {
+		case "telegram":
+			if cfg.Telegram == nil {
+				return nil, fmt.Errorf("notifications[%d]: type telegram requires a telegram config block", i)
+			}
+			sinks = append(sinks, NewTelegramSink(*cfg.Telegram))
+		case "discord":
+			if cfg.Discord == nil {
+				return nil, fmt.Errorf("notifications[%d]: type discord requires a discord config block", i)
+			}
+			sinks = append(sinks, NewDiscordSink(*cfg.Discord))
+		case "smtp":
+			if cfg.SMTP == nil {
+				return nil, fmt.Errorf("notifications[%d]: type smtp requires an smtp config block", i)
+			}
+			sinks = append(sinks, NewSMTPSink(*cfg.SMTP))
+		case "webhook":
+			if cfg.Webhook == nil {
+				return nil, fmt.Errorf("notifications[%d]: type webhook requires a webhook config block", i)
+			}
+			sinks = append(sinks, NewWebhookSink(*cfg.Webhook))
+		default:
+			return nil, fmt.Errorf("notifications[%d]: unknown type %q", i, cfg.Type)
+		}
+	}
+
+	return sinks, nil
+}
+
+// Dispatch notifies every sink, collecting (rather than stopping on) the
+// first error so a broken sink doesn't prevent the others from firing.
+func Dispatch(ctx context.Context, sinks []Sink, summary Summary) []error {
+	var errs []error
+	for _, sink := range sinks {
+		if err := sink.Notify(ctx, summary); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+const messageTemplate = `Scheduling run for {{.Location}} on {{.Date}}
+Successes: {{len .Successes}}
+Failures: {{len .Failures}}
+{{range .Successes}}{{.Link}}
+{{end}}`
+
+// renderMessage renders the shared plain-text template used by every
+// built-in sink.
+func renderMessage(summary Summary) (string, error) {
+	tmpl, err := template.New("notification").Parse(messageTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse notification template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, summary); err != nil {
+		return "", fmt.Errorf("failed to render notification template: %w", err)
+	}
+
+	return buf.String(), nil
+}
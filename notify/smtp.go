@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPConfig configures delivery via email over SMTP.
+type SMTPConfig struct {
+	Host     string   `yaml:"host"`
+	Port     int      `yaml:"port"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+}
+
+type smtpSink struct {
+	config SMTPConfig
+}
+
+// NewSMTPSink builds a Sink that emails the summary via SMTP with PLAIN auth.
+func NewSMTPSink(config SMTPConfig) Sink {
+	return &smtpSink{config: config}
+}
+
+func (s *smtpSink) Notify(ctx context.Context, summary Summary) error {
+	message, err := renderMessage(summary)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Scheduling run for %s on %s", summary.Location, summary.Date)
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s",
+		s.config.From, strings.Join(s.config.To, ", "), subject, message)
+
+	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
+	auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+
+	if err := smtp.SendMail(addr, auth, s.config.From, s.config.To, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send notification email: %w", err)
+	}
+
+	return nil
+}
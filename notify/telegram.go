@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TelegramConfig configures delivery via the Telegram Bot API.
+type TelegramConfig struct {
+	BotToken string `yaml:"bot_token"`
+	ChatID   string `yaml:"chat_id"`
+}
+
+type telegramSink struct {
+	config TelegramConfig
+	client *http.Client
+}
+
+// NewTelegramSink builds a Sink that posts to a Telegram chat via the Bot API.
+func NewTelegramSink(config TelegramConfig) Sink {
+	return &telegramSink{config: config, client: &http.Client{}}
+}
+
+func (t *telegramSink) Notify(ctx context.Context, summary Summary) error {
+	message, err := renderMessage(summary)
+	if err != nil {
+		return err
+	}
+
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.config.BotToken)
+
+	form := url.Values{}
+	form.Set("chat_id", t.config.ChatID)
+	form.Set("text", message)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to build Telegram request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Telegram request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Telegram API returned HTTP %d", resp.StatusCode)
+	}
+
+	var result struct {
+		OK bool `json:"ok"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && !result.OK {
+		return fmt.Errorf("Telegram API reported failure")
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// WebhookConfig configures delivery via a generic JSON webhook, optionally
+// signed with HMAC-SHA256 so the receiver can verify authenticity.
+type WebhookConfig struct {
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret"`
+}
+
+type webhookPayload struct {
+	Location  string            `json:"location"`
+	Date      string            `json:"date"`
+	Successes []BookingResponse `json:"successes"`
+	Failures  int               `json:"failures"`
+}
+
+type webhookSink struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookSink builds a Sink that POSTs a JSON summary to an arbitrary URL.
+func NewWebhookSink(config WebhookConfig) Sink {
+	return &webhookSink{config: config, client: &http.Client{}}
+}
+
+func (w *webhookSink) Notify(ctx context.Context, summary Summary) error {
+	payload, err := json.Marshal(webhookPayload{
+		Location:  summary.Location,
+		Date:      summary.Date,
+		Successes: summary.Successes,
+		Failures:  len(summary.Failures),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if w.config.Secret != "" {
+		req.Header.Set("X-Signature-256", fmt.Sprintf("sha256=%s", sign(w.config.Secret, payload)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned HTTP %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// sign returns the hex-encoded HMAC-SHA256 of payload using secret.
+func sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ruangustavo/itep-rn-scheduling/metrics"
+)
+
+const (
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 5 * time.Second
+
+	// successStreakForThrottleUp is how many consecutive successful
+	// requests are needed before the concurrency limit is additively
+	// increased again.
+	successStreakForThrottleUp = 10
+
+	// throttleStreakForThrottleDown is how many consecutive 429/5xx
+	// responses are needed before the concurrency limit is halved, so a
+	// single transient error doesn't immediately cut concurrency.
+	throttleStreakForThrottleDown = 3
+)
+
+// requestTimeout returns the per-attempt deadline applied to every HTTP
+// call, similar to the reqTimeout pattern used by the maps-booking-v3 API
+// client.
+func (s *Scheduler) requestTimeout() time.Duration {
+	if s.config.RequestTimeoutSeconds > 0 {
+		return time.Duration(s.config.RequestTimeoutSeconds) * time.Second
+	}
+	return 10 * time.Second
+}
+
+// cancelOnCloseBody cancels its associated context once the response body
+// is closed, so a successful request's per-attempt deadline doesn't expire
+// out from under the caller while it's still reading the body, but is still
+// released once the caller is done with it.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// doRequest builds and executes an HTTP request with bounded exponential
+// backoff and full jitter, retrying on network errors, HTTP 429, and 5xx
+// responses. It honors Retry-After when the server sends one, applies a
+// fresh per-attempt deadline derived from ctx, and gives up immediately if
+// ctx is canceled.
+func (s *Scheduler) doRequest(ctx context.Context, method, url string, body []byte, headers map[string]string) (*http.Response, error) {
+	maxAttempts := s.config.MaxRetries
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	var lastErr error
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, s.requestTimeout())
+
+		var reader io.Reader
+		if body != nil {
+			reader = bytes.NewReader(body)
+		}
+
+		req, err := http.NewRequestWithContext(attemptCtx, method, url, reader)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to build request: %w", err)
+		}
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+
+		metrics.Attempts.Inc()
+		resp, err := s.httpClient.Do(req)
+
+		if err == nil && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			if resp.StatusCode < 400 {
+				metrics.Successes.Inc()
+				s.recordRequestSuccess()
+			}
+			resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		var retryAfter time.Duration
+		if err != nil {
+			lastErr = err
+		} else {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				metrics.RateLimited.Inc()
+			}
+			if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+				s.recordRequestThrottled()
+			}
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			lastErr = fmt.Errorf("HTTP error %d", resp.StatusCode)
+			resp.Body.Close()
+		}
+		cancel()
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if attempt == maxAttempts-1 {
+			break
+		}
+
+		delay := retryAfter
+		if delay <= 0 {
+			delay = fullJitterBackoff(attempt)
+		}
+
+		metrics.Retries.Inc()
+		log.Printf("Request to %s failed (attempt %d/%d): %v; retrying in %v",
+			url, attempt+1, maxAttempts, lastErr, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, fmt.Errorf("request failed after %d attempts: %w", maxAttempts, lastErr)
+}
+
+// fullJitterBackoff returns a random delay in [0, min(cap, base*2^attempt)),
+// per the "full jitter" strategy.
+func fullJitterBackoff(attempt int) time.Duration {
+	backoff := retryBaseDelay * time.Duration(1<<uint(attempt))
+	if backoff <= 0 || backoff > retryMaxDelay {
+		backoff = retryMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+// parseRetryAfter parses a Retry-After header, which may be either a number
+// of seconds or an HTTP date. It returns 0 if value can't be parsed.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if when, err := http.ParseTime(value); err == nil {
+		return time.Until(when)
+	}
+
+	return 0
+}
+
+// adaptiveSemaphore is a concurrency limiter whose limit moves with an
+// AIMD (additive increase / multiplicative decrease) loop: it halves on
+// repeated rate limiting and grows by one after a sustained run of
+// successes.
+type adaptiveSemaphore struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	limit int
+	inUse int
+	min   int
+	max   int
+
+	successStreak  int
+	throttleStreak int
+}
+
+func newAdaptiveSemaphore(initial, min, max int) *adaptiveSemaphore {
+	s := &adaptiveSemaphore{limit: initial, min: min, max: max}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// acquire blocks until a slot is free or ctx is canceled, in which case it
+// returns ctx.Err() without acquiring.
+func (a *adaptiveSemaphore) acquire(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		a.mu.Lock()
+		a.cond.Broadcast()
+		a.mu.Unlock()
+	})
+	defer stop()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.inUse >= a.limit {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		a.cond.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	a.inUse++
+	return nil
+}
+
+func (a *adaptiveSemaphore) release() {
+	a.mu.Lock()
+	a.inUse--
+	a.cond.Signal()
+	a.mu.Unlock()
+}
+
+// throttleDown halves the concurrency limit, never going below min, but
+// only once throttleStreakForThrottleDown consecutive 429/5xx responses
+// have been seen, so a single transient error doesn't cut concurrency.
+func (a *adaptiveSemaphore) throttleDown() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.successStreak = 0
+
+	a.throttleStreak++
+	if a.throttleStreak < throttleStreakForThrottleDown {
+		return
+	}
+	a.throttleStreak = 0
+
+	newLimit := a.limit / 2
+	if newLimit < a.min {
+		newLimit = a.min
+	}
+	if newLimit != a.limit {
+		log.Printf("Reducing concurrency limit from %d to %d after repeated rate limiting", a.limit, newLimit)
+		a.limit = newLimit
+		a.cond.Broadcast()
+	}
+}
+
+// recordSuccess grows the concurrency limit by one for every
+// successStreakForThrottleUp consecutive successes, never exceeding max.
+func (a *adaptiveSemaphore) recordSuccess() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.throttleStreak = 0
+
+	if a.limit >= a.max {
+		a.successStreak = 0
+		return
+	}
+
+	a.successStreak++
+	if a.successStreak >= successStreakForThrottleUp {
+		a.successStreak = 0
+		a.limit++
+		log.Printf("Increasing concurrency limit to %d after sustained success", a.limit)
+		a.cond.Broadcast()
+	}
+}
+
+func (s *Scheduler) recordRequestThrottled() {
+	s.semaphore.throttleDown()
+}
+
+func (s *Scheduler) recordRequestSuccess() {
+	s.semaphore.recordSuccess()
+}
@@ -0,0 +1,149 @@
+// Package transport provides http.RoundTripper implementations for
+// recording real ITEP API traffic to disk and replaying it later, so
+// tests can exercise the scheduler's HTTP-calling code without hitting
+// the live API.
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Interaction is one recorded request/response pair.
+type Interaction struct {
+	Method       string            `json:"method"`
+	URL          string            `json:"url"`
+	RequestBody  string            `json:"request_body,omitempty"`
+	StatusCode   int               `json:"status_code"`
+	Header       map[string]string `json:"header,omitempty"`
+	ResponseBody string            `json:"response_body"`
+}
+
+// RecordingRoundTripper forwards every request to Next (or
+// http.DefaultTransport if Next is nil) and, after each one, rewrites the
+// JSON file at Path with every request/response pair it has recorded so
+// far. Any content already in the file at Path is overwritten.
+type RecordingRoundTripper struct {
+	Next http.RoundTripper
+	Path string
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+func (r *RecordingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	next := r.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := make(map[string]string, len(resp.Header))
+	for key := range resp.Header {
+		header[key] = resp.Header.Get(key)
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Method:       req.Method,
+		URL:          req.URL.String(),
+		RequestBody:  string(reqBody),
+		StatusCode:   resp.StatusCode,
+		Header:       header,
+		ResponseBody: string(respBody),
+	})
+	saveErr := r.save()
+	r.mu.Unlock()
+	if saveErr != nil {
+		return nil, fmt.Errorf("failed to persist recorded interaction: %w", saveErr)
+	}
+
+	return resp, nil
+}
+
+// save writes every interaction recorded so far to r.Path. Callers must
+// hold r.mu.
+func (r *RecordingRoundTripper) save() error {
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.Path, data, 0644)
+}
+
+// ReplayRoundTripper serves back interactions recorded by
+// RecordingRoundTripper, matching each request to the first unused
+// recorded interaction with the same method and URL.
+type ReplayRoundTripper struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         map[int]bool
+}
+
+// LoadReplayRoundTripper reads interactions recorded to path.
+func LoadReplayRoundTripper(path string) (*ReplayRoundTripper, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture file: %w", err)
+	}
+
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture file: %w", err)
+	}
+
+	return &ReplayRoundTripper{interactions: interactions, used: make(map[int]bool)}, nil
+}
+
+func (r *ReplayRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.interactions {
+		if r.used[i] || interaction.Method != req.Method || interaction.URL != req.URL.String() {
+			continue
+		}
+		r.used[i] = true
+
+		header := make(http.Header, len(interaction.Header))
+		for key, value := range interaction.Header {
+			header.Set(key, value)
+		}
+
+		return &http.Response{
+			StatusCode: interaction.StatusCode,
+			Status:     http.StatusText(interaction.StatusCode),
+			Header:     header,
+			Body:       io.NopCloser(bytes.NewReader([]byte(interaction.ResponseBody))),
+			Request:    req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, req.URL.String())
+}